@@ -0,0 +1,220 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package canonicalizer implements JSON Canonicalization Scheme (JCS) as defined by RFC 8785:
+// https://tools.ietf.org/html/rfc8785.
+package canonicalizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// MarshalCanonical returns the JCS-canonicalized JSON encoding of v. v may be a Go value to be
+// marshaled with encoding/json, or a []byte containing JSON to be re-canonicalized.
+func MarshalCanonical(v interface{}) ([]byte, error) {
+	raw, err := toJSONBytes(v)
+	if err != nil {
+		return nil, err
+	}
+
+	var data interface{}
+
+	decoder := json.NewDecoder(bytes.NewReader(raw))
+	decoder.UseNumber()
+
+	if err := decoder.Decode(&data); err != nil {
+		return nil, fmt.Errorf("failed to decode json for canonicalization: %w", err)
+	}
+
+	buf := &bytes.Buffer{}
+
+	if err := encodeCanonical(buf, data); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+func toJSONBytes(v interface{}) ([]byte, error) {
+	if raw, ok := v.([]byte); ok {
+		return raw, nil
+	}
+
+	raw, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal value for canonicalization: %w", err)
+	}
+
+	return raw, nil
+}
+
+func encodeCanonical(buf *bytes.Buffer, v interface{}) error { //nolint: gocyclo
+	switch val := v.(type) {
+	case nil:
+		buf.WriteString("null")
+	case bool:
+		if val {
+			buf.WriteString("true")
+		} else {
+			buf.WriteString("false")
+		}
+	case json.Number:
+		n, err := canonicalNumber(val)
+		if err != nil {
+			return err
+		}
+
+		buf.WriteString(n)
+	case string:
+		return encodeCanonicalString(buf, val)
+	case []interface{}:
+		return encodeCanonicalArray(buf, val)
+	case map[string]interface{}:
+		return encodeCanonicalObject(buf, val)
+	default:
+		return fmt.Errorf("unsupported type %T for canonicalization", v)
+	}
+
+	return nil
+}
+
+func encodeCanonicalArray(buf *bytes.Buffer, arr []interface{}) error {
+	buf.WriteByte('[')
+
+	for i, e := range arr {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := encodeCanonical(buf, e); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte(']')
+
+	return nil
+}
+
+func encodeCanonicalObject(buf *bytes.Buffer, obj map[string]interface{}) error {
+	keys := make([]string, 0, len(obj))
+	for k := range obj {
+		keys = append(keys, k)
+	}
+
+	// RFC 8785 section 3.2.3: object keys are sorted by UTF-16 code unit. Go string comparison
+	// compares UTF-8 bytes, which agrees with UTF-16 code unit order for the entire BMP.
+	sort.Strings(keys)
+
+	buf.WriteByte('{')
+
+	for i, k := range keys {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+
+		if err := encodeCanonicalString(buf, k); err != nil {
+			return err
+		}
+
+		buf.WriteByte(':')
+
+		if err := encodeCanonical(buf, obj[k]); err != nil {
+			return err
+		}
+	}
+
+	buf.WriteByte('}')
+
+	return nil
+}
+
+// encodeCanonicalString writes s per RFC 8785 section 3.2.2.2, escaping the same characters as
+// encoding/json but without its default HTML escaping of '<', '>' and '&'.
+func encodeCanonicalString(buf *bytes.Buffer, s string) error {
+	encoder := json.NewEncoder(buf)
+	encoder.SetEscapeHTML(false)
+
+	if err := encoder.Encode(s); err != nil {
+		return fmt.Errorf("failed to encode canonical string: %w", err)
+	}
+
+	buf.Truncate(buf.Len() - 1) // Encode appends a trailing newline
+
+	return nil
+}
+
+// canonicalNumber formats n per RFC 8785 section 3.2.2.3, which defers to the ECMA-262
+// Number::toString algorithm. That algorithm picks the shortest round-tripping decimal digit
+// string for the value, then chooses fixed or exponential notation based on the digit count and
+// decimal-point position — not on the value's magnitude relative to 2^53 (2^53 only bounds exact
+// integer representability in float64, it is not where ECMA-262 switches to exponential form).
+func canonicalNumber(n json.Number) (string, error) {
+	f, err := n.Float64()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse json number %q for canonicalization: %w", n, err)
+	}
+
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return "", fmt.Errorf("json number %q is not finite and cannot be canonicalized", n)
+	}
+
+	if f == 0 {
+		return "0", nil // ECMA-262 prints both 0 and -0 as "0"
+	}
+
+	neg := f < 0
+	if neg {
+		f = -f
+	}
+
+	// strconv's shortest round-tripping decimal digits, in the form d.ddd...e±dd.
+	parts := strings.SplitN(strconv.FormatFloat(f, 'e', -1, 64), "e", 2)
+
+	exp, err := strconv.Atoi(parts[1])
+	if err != nil {
+		return "", fmt.Errorf("failed to parse exponent of %q for canonicalization: %w", n, err)
+	}
+
+	digits := strings.Replace(parts[0], ".", "", 1)
+	k := len(digits)
+	point := exp + 1 // ECMA-262's "n": value == digits * 10^(point-k)
+
+	var out string
+
+	switch {
+	case k <= point && point <= 21:
+		out = digits + strings.Repeat("0", point-k)
+	case 0 < point && point <= 21:
+		out = digits[:point] + "." + digits[point:]
+	case -6 < point && point <= 0:
+		out = "0." + strings.Repeat("0", -point) + digits
+	default:
+		mantissa := digits[:1]
+		if k > 1 {
+			mantissa += "." + digits[1:]
+		}
+
+		e, sign := point-1, "+"
+		if e < 0 {
+			e, sign = -e, "-"
+		}
+
+		out = mantissa + "e" + sign + strconv.Itoa(e)
+	}
+
+	if neg {
+		out = "-" + out
+	}
+
+	return out, nil
+}
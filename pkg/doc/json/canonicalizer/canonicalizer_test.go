@@ -0,0 +1,85 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package canonicalizer
+
+import (
+	"encoding/json"
+	"math"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshalCanonical(t *testing.T) {
+	t.Run("sorts object keys lexicographically regardless of input order", func(t *testing.T) {
+		out, err := MarshalCanonical([]byte(`{"b":1,"a":2,"c":3}`))
+		require.NoError(t, err)
+		require.Equal(t, `{"a":2,"b":1,"c":3}`, string(out))
+	})
+
+	t.Run("escapes strings without HTML-escaping reserved characters", func(t *testing.T) {
+		out, err := MarshalCanonical([]byte(`{"a":"<b>&\"c\""}`))
+		require.NoError(t, err)
+		require.Equal(t, `{"a":"<b>&\"c\""}`, string(out))
+	})
+
+	t.Run("formats nested arrays and objects canonically", func(t *testing.T) {
+		out, err := MarshalCanonical([]byte(`{"arr":[3,1,2],"nested":{"z":1,"a":2}}`))
+		require.NoError(t, err)
+		require.Equal(t, `{"arr":[3,1,2],"nested":{"a":2,"z":1}}`, string(out))
+	})
+
+	t.Run("rejects non-finite numbers", func(t *testing.T) {
+		_, err := MarshalCanonical(map[string]interface{}{"a": math.NaN()})
+		require.Error(t, err)
+	})
+}
+
+func TestCanonicalNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		in   json.Number
+		want string
+	}{
+		{"small integer", json.Number("0"), "0"},
+		{"negative integer", json.Number("-42"), "-42"},
+		{"integer just under the old 1e15 threshold", json.Number("999999999999999"), "999999999999999"},
+		{"integer at the old 1e15 threshold", json.Number("1000000000000000"), "1000000000000000"},
+		{"integer between 1e15 and 2^53", json.Number("4503599627370495"), "4503599627370495"},
+		{"integer exactly at 2^53", json.Number("9007199254740992"), "9007199254740992"},
+		// regression: these are past 2^53 (so not exactly representable as int64 by the old
+		// fast-path check) but still well under ECMA-262's ~1e21 exponential-notation cutoff, and
+		// must still print as plain integers, not "9.007199254740994e+15" / "1e+16".
+		{"integer just past 2^53", json.Number("9007199254740994"), "9007199254740994"},
+		{"integer at 1e16", json.Number("10000000000000000"), "10000000000000000"},
+		{"integer at 1e20, the last magnitude ECMA-262 prints without an exponent",
+			json.Number("100000000000000000000"), "100000000000000000000"},
+		{"integer at 1e21, where ECMA-262 switches to exponential notation",
+			json.Number("1000000000000000000000"), "1e+21"},
+		{"integer well past the exponential cutoff", json.Number("123000000000000000000000"), "1.23e+23"},
+		{"fractional value", json.Number("1.5"), "1.5"},
+		{"fractional value needing shortest round-trip form", json.Number("0.1"), "0.1"},
+		{"small fraction still in fixed notation range", json.Number("0.000001"), "0.000001"},
+		{"small fraction past the fixed notation range uses exponential notation",
+			json.Number("0.0000001"), "1e-7"},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := canonicalNumber(tt.in)
+			require.NoError(t, err)
+			require.Equal(t, tt.want, got)
+		})
+	}
+
+	t.Run("rejects NaN", func(t *testing.T) {
+		_, err := canonicalNumber(json.Number("NaN"))
+		require.Error(t, err)
+	})
+}
@@ -0,0 +1,76 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package vdr
+
+import (
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+)
+
+// MockVDR implements a mock vdr.VDR, for exercising registrar/resolver handlers without a real
+// trustbloc VDR.
+type MockVDR struct {
+	ReadFunc       func(didID string, opts ...vdr.ResolveDIDOption) (*did.DocResolution, error)
+	CreateFunc     func(didDoc *did.Doc, opts ...vdr.DIDMethodOption) (*did.DocResolution, error)
+	UpdateFunc     func(didDoc *did.Doc, opts ...vdr.DIDMethodOption) error
+	DeactivateFunc func(didID string, opts ...vdr.DIDMethodOption) error
+	AcceptFunc     func(method string) bool
+	CloseFunc      func() error
+}
+
+// Read resolves a DID document.
+func (m *MockVDR) Read(didID string, opts ...vdr.ResolveDIDOption) (*did.DocResolution, error) {
+	if m.ReadFunc != nil {
+		return m.ReadFunc(didID, opts...)
+	}
+
+	return nil, nil
+}
+
+// Create creates a DID document.
+func (m *MockVDR) Create(didDoc *did.Doc, opts ...vdr.DIDMethodOption) (*did.DocResolution, error) {
+	if m.CreateFunc != nil {
+		return m.CreateFunc(didDoc, opts...)
+	}
+
+	return nil, nil
+}
+
+// Update updates a DID document.
+func (m *MockVDR) Update(didDoc *did.Doc, opts ...vdr.DIDMethodOption) error {
+	if m.UpdateFunc != nil {
+		return m.UpdateFunc(didDoc, opts...)
+	}
+
+	return nil
+}
+
+// Deactivate deactivates a DID document.
+func (m *MockVDR) Deactivate(didID string, opts ...vdr.DIDMethodOption) error {
+	if m.DeactivateFunc != nil {
+		return m.DeactivateFunc(didID, opts...)
+	}
+
+	return nil
+}
+
+// Accept reports whether this VDR accepts the given did method.
+func (m *MockVDR) Accept(method string) bool {
+	if m.AcceptFunc != nil {
+		return m.AcceptFunc(method)
+	}
+
+	return true
+}
+
+// Close shuts down the VDR.
+func (m *MockVDR) Close() error {
+	if m.CloseFunc != nil {
+		return m.CloseFunc()
+	}
+
+	return nil
+}
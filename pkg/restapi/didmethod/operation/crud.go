@@ -0,0 +1,320 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"math/big"
+	"net/http"
+
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/trustbloc"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	log "github.com/sirupsen/logrus"
+)
+
+const (
+	updatePath     = registerBasePath + "/update"
+	deactivatePath = registerBasePath + "/deactivate"
+	recoverPath    = registerBasePath + "/recover"
+)
+
+func (o *Operation) updateDIDHandler(rw http.ResponseWriter, req *http.Request) {
+	data := UpdateDIDRequest{}
+
+	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+		o.writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+
+		return
+	}
+
+	didState := DIDState{}
+
+	signingKey, err := getPrivateKey(data.Secret.SigningKeyType, data.Secret.SigningKeyValue)
+	if err != nil {
+		o.writeResponse(rw, UpdateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didMethodOpt := []vdr.DIDMethodOption{vdr.WithOption(trustbloc.SigningKeyOpt, signingKey)}
+
+	didDoc := did.Doc{ID: data.DIDDocumentOperation.DID}
+
+	keysID, err := buildVerificationMethods(&didDoc, data.DIDDocumentOperation.AddPublicKeys)
+	if err != nil {
+		o.writeResponse(rw, UpdateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	buildServices(&didDoc, data.DIDDocumentOperation.AddServices)
+
+	if len(data.DIDDocumentOperation.RemovePublicKeys) > 0 {
+		didMethodOpt = append(didMethodOpt,
+			vdr.WithOption(trustbloc.RemovePublicKeysOpt, data.DIDDocumentOperation.RemovePublicKeys))
+	}
+
+	if len(data.DIDDocumentOperation.RemoveServices) > 0 {
+		didMethodOpt = append(didMethodOpt,
+			vdr.WithOption(trustbloc.RemoveServicesOpt, data.DIDDocumentOperation.RemoveServices))
+	}
+
+	nextUpdateKeys, err := nextUpdateCommitmentOpt(data.Secret)
+	if err != nil {
+		o.writeResponse(rw, UpdateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didMethodOpt = append(didMethodOpt, nextUpdateKeys...)
+
+	if err := o.blocVDRI.Update(&didDoc, didMethodOpt...); err != nil {
+		log.Errorf("failed to update did doc : %s", err.Error())
+
+		o.writeResponse(rw, UpdateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: fmt.Sprintf("failed to update did doc : %s", err.Error()),
+				State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didState.Identifier = data.DIDDocumentOperation.DID
+	didState.State = RegistrationStateFinished
+	didState.Secret = Secret{Keys: createKeys(keysID, data.DIDDocumentOperation.DID)}
+
+	o.writeResponse(rw, UpdateDIDResponse{JobID: data.JobID, DIDState: didState})
+}
+
+func (o *Operation) deactivateDIDHandler(rw http.ResponseWriter, req *http.Request) {
+	data := DeactivateDIDRequest{}
+
+	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+		o.writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+
+		return
+	}
+
+	signingKey, err := getPrivateKey(data.Secret.SigningKeyType, data.Secret.SigningKeyValue)
+	if err != nil {
+		o.writeResponse(rw, DeactivateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didMethodOpt := []vdr.DIDMethodOption{vdr.WithOption(trustbloc.SigningKeyOpt, signingKey)}
+
+	if err := o.blocVDRI.Deactivate(data.DID, didMethodOpt...); err != nil {
+		log.Errorf("failed to deactivate did doc : %s", err.Error())
+
+		o.writeResponse(rw, DeactivateDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: fmt.Sprintf("failed to deactivate did doc : %s", err.Error()),
+				State: RegistrationStateFailure}})
+
+		return
+	}
+
+	o.writeResponse(rw, DeactivateDIDResponse{JobID: data.JobID,
+		DIDState: DIDState{Identifier: data.DID, State: RegistrationStateFinished}})
+}
+
+func (o *Operation) recoverDIDHandler(rw http.ResponseWriter, req *http.Request) {
+	data := RecoverDIDRequest{}
+
+	if err := json.NewDecoder(req.Body).Decode(&data); err != nil {
+		o.writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf(invalidRequestErrMsg+": %s", err.Error()))
+
+		return
+	}
+
+	signingKey, err := getPrivateKey(data.Secret.SigningKeyType, data.Secret.SigningKeyValue)
+	if err != nil {
+		o.writeResponse(rw, RecoverDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didMethodOpt := []vdr.DIDMethodOption{vdr.WithOption(trustbloc.SigningKeyOpt, signingKey)}
+
+	didDoc := did.Doc{ID: data.DID}
+
+	keysID, err := buildVerificationMethods(&didDoc, data.DIDDocument.PublicKey)
+	if err != nil {
+		o.writeResponse(rw, RecoverDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	buildServices(&didDoc, data.DIDDocument.Service)
+
+	nextKeys, err := nextCommitmentOpts(data.Secret.NextUpdateKey, trustbloc.UpdatePublicKeyOpt,
+		data.Secret.NextRecoveryKey, trustbloc.RecoveryPublicKeyOpt)
+	if err != nil {
+		o.writeResponse(rw, RecoverDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: err.Error(), State: RegistrationStateFailure}})
+
+		return
+	}
+
+	didMethodOpt = append(didMethodOpt, nextKeys...)
+
+	if err := o.blocVDRI.Update(&didDoc, didMethodOpt...); err != nil {
+		log.Errorf("failed to recover did doc : %s", err.Error())
+
+		o.writeResponse(rw, RecoverDIDResponse{JobID: data.JobID,
+			DIDState: DIDState{Reason: fmt.Sprintf("failed to recover did doc : %s", err.Error()),
+				State: RegistrationStateFailure}})
+
+		return
+	}
+
+	o.writeResponse(rw, RecoverDIDResponse{JobID: data.JobID, DIDState: DIDState{Identifier: data.DID,
+		State: RegistrationStateFinished, Secret: Secret{Keys: createKeys(keysID, data.DID)}}})
+}
+
+// buildVerificationMethods adds a verification method for each of publicKeys to didDoc under its
+// requested purposes, and returns the raw key material keyed by key ID for the response Secret.
+func buildVerificationMethods(didDoc *did.Doc, publicKeys []PublicKey) (map[string][]byte, error) {
+	keysID := make(map[string][]byte)
+
+	for _, v := range publicKeys {
+		keyValue, err := base64.StdEncoding.DecodeString(v.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode public key value : %w", err)
+		}
+
+		k, err := getKey(v.KeyType, keyValue)
+		if err != nil {
+			return nil, err
+		}
+
+		jwk, err := jwksupport.JWKFromKey(k)
+		if err != nil {
+			return nil, err
+		}
+
+		vm, err := did.NewVerificationMethodFromJWK(v.ID, v.Type, "", jwk)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, p := range v.Purposes {
+			if err := addVerificationRelationship(didDoc, vm, p); err != nil {
+				return nil, err
+			}
+		}
+
+		keysID[v.ID] = keyValue
+	}
+
+	return keysID, nil
+}
+
+// buildServices adds a did.Service entry to didDoc for each of services.
+func buildServices(didDoc *did.Doc, services []Service) {
+	for _, service := range services {
+		didDoc.Service = append(didDoc.Service, did.Service{ID: service.ID, Type: service.Type,
+			Priority: service.Priority, RecipientKeys: service.RecipientKeys, RoutingKeys: service.RoutingKeys,
+			ServiceEndpoint: service.Endpoint})
+	}
+}
+
+// nextUpdateCommitmentOpt decodes the next update public key for a /1.0/update request into the
+// trustbloc DID method option that sets its commitment. Per Sidetree, a genuine update operation
+// only rolls the update commitment, so a request that also carries a next recovery key is
+// rejected outright rather than silently honored.
+func nextUpdateCommitmentOpt(secret RequestSecret) ([]vdr.DIDMethodOption, error) {
+	if secret.NextRecoveryKey != nil {
+		return nil, fmt.Errorf("a next recovery key may only be set on a recover operation, not an update")
+	}
+
+	return nextCommitmentOpts(secret.NextUpdateKey, trustbloc.UpdatePublicKeyOpt, nil, "")
+}
+
+// nextCommitmentOpts decodes the next update/recovery public keys, if present, into the
+// trustbloc DID method options that set their commitments for the next Sidetree operation.
+func nextCommitmentOpts(nextUpdateKey *PublicKey, updateOptName string,
+	nextRecoveryKey *PublicKey, recoveryOptName string) ([]vdr.DIDMethodOption, error) {
+	var opts []vdr.DIDMethodOption
+
+	if nextUpdateKey != nil {
+		keyValue, err := base64.StdEncoding.DecodeString(nextUpdateKey.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode next update key value : %w", err)
+		}
+
+		k, err := getKey(nextUpdateKey.KeyType, keyValue)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, vdr.WithOption(updateOptName, k))
+	}
+
+	if nextRecoveryKey != nil {
+		keyValue, err := base64.StdEncoding.DecodeString(nextRecoveryKey.Value)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode next recovery key value : %w", err)
+		}
+
+		k, err := getKey(nextRecoveryKey.KeyType, keyValue)
+		if err != nil {
+			return nil, err
+		}
+
+		opts = append(opts, vdr.WithOption(recoveryOptName, k))
+	}
+
+	return opts, nil
+}
+
+// getPrivateKey decodes a base64-encoded current update/recovery private key so it can sign a
+// Sidetree update/deactivate/recover operation.
+func getPrivateKey(keyType, value string) (crypto.Signer, error) {
+	keyValue, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode signing key value : %w", err)
+	}
+
+	switch keyType {
+	case Ed25519KeyType:
+		if len(keyValue) == ed25519.SeedSize {
+			return ed25519.NewKeyFromSeed(keyValue), nil
+		}
+
+		if len(keyValue) != ed25519.PrivateKeySize {
+			return nil, fmt.Errorf("invalid ed25519 signing key length: %d", len(keyValue))
+		}
+
+		return ed25519.PrivateKey(keyValue), nil
+	case P256KeyType:
+		if len(keyValue) != 32 {
+			return nil, fmt.Errorf("invalid p256 signing key length: %d", len(keyValue))
+		}
+
+		curve := elliptic.P256()
+		d := new(big.Int).SetBytes(keyValue)
+		x, y := curve.ScalarBaseMult(keyValue)
+
+		return &ecdsa.PrivateKey{PublicKey: ecdsa.PublicKey{Curve: curve, X: x, Y: y}, D: d}, nil
+	default:
+		return nil, fmt.Errorf("invalid key type: %s", keyType)
+	}
+}
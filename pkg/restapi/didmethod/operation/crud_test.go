@@ -0,0 +1,254 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/hyperledger/aries-framework-go-ext/component/vdr/trustbloc"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/framework/aries/api/vdr"
+	"github.com/stretchr/testify/require"
+
+	mockvdr "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/vdr"
+)
+
+func signingSecret() RequestSecret {
+	seed := make([]byte, ed25519.SeedSize)
+
+	return RequestSecret{SigningKeyType: Ed25519KeyType, SigningKeyValue: base64.StdEncoding.EncodeToString(seed)}
+}
+
+func doHandlerRequest(t *testing.T, handler func(http.ResponseWriter, *http.Request),
+	body interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+
+	raw, err := json.Marshal(body)
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	handler(rw, httptest.NewRequest("POST", "/", bytes.NewReader(raw)))
+
+	return rw
+}
+
+func TestNextUpdateCommitmentOpt(t *testing.T) {
+	t.Run("rejects a next recovery key on an update request", func(t *testing.T) {
+		secret := RequestSecret{NextRecoveryKey: &PublicKey{KeyType: Ed25519KeyType}}
+
+		opts, err := nextUpdateCommitmentOpt(secret)
+		require.Error(t, err)
+		require.Nil(t, opts)
+		require.Contains(t, err.Error(), "recover")
+	})
+
+	t.Run("builds an update commitment option when no recovery key is present", func(t *testing.T) {
+		pub, _, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		secret := RequestSecret{NextUpdateKey: &PublicKey{
+			KeyType: Ed25519KeyType, Value: base64.StdEncoding.EncodeToString(pub),
+		}}
+
+		opts, err := nextUpdateCommitmentOpt(secret)
+		require.NoError(t, err)
+		require.Len(t, opts, 1)
+	})
+}
+
+func TestGetPrivateKey(t *testing.T) {
+	t.Run("ed25519 seed", func(t *testing.T) {
+		seed := make([]byte, ed25519.SeedSize)
+
+		signer, err := getPrivateKey(Ed25519KeyType, base64.StdEncoding.EncodeToString(seed))
+		require.NoError(t, err)
+		require.NotNil(t, signer)
+	})
+
+	t.Run("ed25519 full private key", func(t *testing.T) {
+		_, priv, err := ed25519.GenerateKey(nil)
+		require.NoError(t, err)
+
+		signer, err := getPrivateKey(Ed25519KeyType, base64.StdEncoding.EncodeToString(priv))
+		require.NoError(t, err)
+		require.NotNil(t, signer)
+	})
+
+	t.Run("ed25519 malformed length fails cleanly instead of panicking later in Sign", func(t *testing.T) {
+		malformed := make([]byte, 10)
+
+		signer, err := getPrivateKey(Ed25519KeyType, base64.StdEncoding.EncodeToString(malformed))
+		require.Error(t, err)
+		require.Nil(t, signer)
+		require.Contains(t, err.Error(), "invalid ed25519 signing key length")
+	})
+
+	t.Run("p256 valid length", func(t *testing.T) {
+		key := make([]byte, 32)
+		key[31] = 1
+
+		signer, err := getPrivateKey(P256KeyType, base64.StdEncoding.EncodeToString(key))
+		require.NoError(t, err)
+		require.NotNil(t, signer)
+	})
+
+	t.Run("p256 malformed length fails cleanly instead of deriving the wrong key", func(t *testing.T) {
+		malformed := make([]byte, 10)
+
+		signer, err := getPrivateKey(P256KeyType, base64.StdEncoding.EncodeToString(malformed))
+		require.Error(t, err)
+		require.Nil(t, signer)
+		require.Contains(t, err.Error(), "invalid p256 signing key length")
+	})
+}
+
+func TestUpdateDIDHandler(t *testing.T) {
+	t.Run("rejects an undecodable request body", func(t *testing.T) {
+		op := &Operation{}
+
+		rw := httptest.NewRecorder()
+		op.updateDIDHandler(rw, httptest.NewRequest("POST", "/", bytes.NewReader([]byte("{"))))
+
+		require.Equal(t, http.StatusBadRequest, rw.Code)
+	})
+
+	t.Run("finishes when the VDR update succeeds", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{}}
+
+		rw := doHandlerRequest(t, op.updateDIDHandler, UpdateDIDRequest{
+			DIDDocumentOperation: DIDDocumentOperation{DID: "did:trustbloc:testnet:abc123"},
+			Secret:               signingSecret(),
+		})
+
+		var resp UpdateDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFinished, resp.DIDState.State)
+		require.Equal(t, "did:trustbloc:testnet:abc123", resp.DIDState.Identifier)
+	})
+
+	t.Run("fails when the VDR update errors", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{
+			UpdateFunc: func(didDoc *did.Doc, opts ...vdr.DIDMethodOption) error {
+				return fmt.Errorf("vdr unreachable")
+			},
+		}}
+
+		rw := doHandlerRequest(t, op.updateDIDHandler, UpdateDIDRequest{
+			DIDDocumentOperation: DIDDocumentOperation{DID: "did:trustbloc:testnet:abc123"},
+			Secret:               signingSecret(),
+		})
+
+		var resp UpdateDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFailure, resp.DIDState.State)
+		require.Contains(t, resp.DIDState.Reason, "vdr unreachable")
+	})
+}
+
+func TestDeactivateDIDHandler(t *testing.T) {
+	t.Run("finishes when the VDR deactivate succeeds", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{}}
+
+		rw := doHandlerRequest(t, op.deactivateDIDHandler, DeactivateDIDRequest{
+			DID: "did:trustbloc:testnet:abc123", Secret: signingSecret(),
+		})
+
+		var resp DeactivateDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFinished, resp.DIDState.State)
+	})
+
+	t.Run("fails when the VDR deactivate errors", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{
+			DeactivateFunc: func(didID string, opts ...vdr.DIDMethodOption) error {
+				return fmt.Errorf("vdr unreachable")
+			},
+		}}
+
+		rw := doHandlerRequest(t, op.deactivateDIDHandler, DeactivateDIDRequest{
+			DID: "did:trustbloc:testnet:abc123", Secret: signingSecret(),
+		})
+
+		var resp DeactivateDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFailure, resp.DIDState.State)
+		require.Contains(t, resp.DIDState.Reason, "vdr unreachable")
+	})
+}
+
+func TestRecoverDIDHandler(t *testing.T) {
+	t.Run("finishes when the VDR update succeeds", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{}}
+
+		rw := doHandlerRequest(t, op.recoverDIDHandler, RecoverDIDRequest{
+			DID: "did:trustbloc:testnet:abc123", Secret: signingSecret(),
+		})
+
+		var resp RecoverDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFinished, resp.DIDState.State)
+	})
+
+	t.Run("fails when the VDR update errors", func(t *testing.T) {
+		op := &Operation{blocVDRI: &mockvdr.MockVDR{
+			UpdateFunc: func(didDoc *did.Doc, opts ...vdr.DIDMethodOption) error {
+				return fmt.Errorf("vdr unreachable")
+			},
+		}}
+
+		rw := doHandlerRequest(t, op.recoverDIDHandler, RecoverDIDRequest{
+			DID: "did:trustbloc:testnet:abc123", Secret: signingSecret(),
+		})
+
+		var resp RecoverDIDResponse
+
+		require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+		require.Equal(t, RegistrationStateFailure, resp.DIDState.State)
+		require.Contains(t, resp.DIDState.Reason, "vdr unreachable")
+	})
+}
+
+func TestBuildServices(t *testing.T) {
+	didDoc := did.Doc{}
+
+	buildServices(&didDoc, []Service{{ID: "svc1", Type: "did-communication", Endpoint: "https://example.com"}})
+
+	require.Len(t, didDoc.Service, 1)
+	require.Equal(t, "svc1", didDoc.Service[0].ID)
+	require.Equal(t, "https://example.com", didDoc.Service[0].ServiceEndpoint)
+}
+
+func TestNextCommitmentOpts(t *testing.T) {
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	key := &PublicKey{KeyType: Ed25519KeyType, Value: base64.StdEncoding.EncodeToString(pub)}
+
+	t.Run("builds both update and recovery options when both keys are present", func(t *testing.T) {
+		opts, err := nextCommitmentOpts(key, trustbloc.UpdatePublicKeyOpt, key, trustbloc.RecoveryPublicKeyOpt)
+		require.NoError(t, err)
+		require.Len(t, opts, 2)
+	})
+
+	t.Run("builds no options when neither key is present", func(t *testing.T) {
+		opts, err := nextCommitmentOpts(nil, trustbloc.UpdatePublicKeyOpt, nil, trustbloc.RecoveryPublicKeyOpt)
+		require.NoError(t, err)
+		require.Len(t, opts, 0)
+	})
+}
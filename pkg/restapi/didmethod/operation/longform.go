@@ -0,0 +1,272 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/hyperledger/aries-framework-go/pkg/doc/did"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/doc/json/canonicalizer"
+)
+
+const (
+	longFormOpt = "longForm"
+
+	addPublicKeysPatch = "add-public-keys"
+	addServicesPatch   = "add-services"
+
+	sha2256Code byte = 18
+)
+
+// suffixData is the Sidetree suffix data of a create operation.
+type suffixData struct {
+	DeltaHash          string `json:"deltaHash"`
+	RecoveryCommitment string `json:"recoveryCommitment"`
+}
+
+// delta is the Sidetree delta of a create operation.
+type delta struct {
+	Patches          []patch `json:"patches"`
+	UpdateCommitment string  `json:"updateCommitment"`
+}
+
+type patch struct {
+	Action     string      `json:"action"`
+	PublicKeys interface{} `json:"publicKeys,omitempty"`
+	Services   interface{} `json:"services,omitempty"`
+}
+
+// initialState is the long-form DID initial state, encoded as the final colon-separated
+// segment of a long-form DID URI.
+type initialState struct {
+	SuffixData suffixData `json:"suffixData"`
+	Delta      delta      `json:"delta"`
+}
+
+// buildLongFormDID constructs a Sidetree long-form (unpublished) DID URI for the given
+// document patches and commitments, following the form
+// did:<method>:<domain>:<short-form-suffix>:<base64url(initial state)>.
+func buildLongFormDID(method, domain string, publicKeys, services interface{},
+	updateCommitment, recoveryCommitment string) (string, error) {
+	deltaModel := delta{
+		Patches:          buildPatches(publicKeys, services),
+		UpdateCommitment: updateCommitment,
+	}
+
+	deltaBytes, err := canonicalizer.MarshalCanonical(deltaModel)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize delta: %w", err)
+	}
+
+	suffixDataModel := suffixData{
+		DeltaHash:          multihash(deltaBytes),
+		RecoveryCommitment: recoveryCommitment,
+	}
+
+	suffixDataBytes, err := canonicalizer.MarshalCanonical(suffixDataModel)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize suffix data: %w", err)
+	}
+
+	suffix := multihash(suffixDataBytes)
+
+	// the initial state itself is plain JSON (not JCS) since it is decoded back into a Go
+	// struct on resolution rather than hashed or signed.
+	stateBytes, err := json.Marshal(initialState{SuffixData: suffixDataModel, Delta: deltaModel})
+	if err != nil {
+		return "", fmt.Errorf("failed to marshal initial state: %w", err)
+	}
+
+	encodedState := base64.RawURLEncoding.EncodeToString(stateBytes)
+
+	return fmt.Sprintf("did:%s:%s:%s:%s", method, domain, suffix, encodedState), nil
+}
+
+func buildPatches(publicKeys, services interface{}) []patch {
+	var patches []patch
+
+	if publicKeys != nil {
+		patches = append(patches, patch{Action: addPublicKeysPatch, PublicKeys: publicKeys})
+	}
+
+	if services != nil {
+		patches = append(patches, patch{Action: addServicesPatch, Services: services})
+	}
+
+	return patches
+}
+
+// parseLongFormDID detects whether did is a Sidetree long-form DID URI (an extra
+// base64url-encoded initial-state segment after the short-form suffix), and if so,
+// decodes and verifies it against the suffix encoded in the DID itself.
+func parseLongFormDID(did string) (*initialState, bool, error) {
+	parts := strings.Split(did, ":")
+	if len(parts) < 5 {
+		return nil, false, nil
+	}
+
+	suffix := parts[len(parts)-2]
+	encodedState := parts[len(parts)-1]
+
+	stateBytes, err := base64.RawURLEncoding.DecodeString(encodedState)
+	if err != nil {
+		return nil, false, nil //nolint:nilerr // not a long-form DID, fall back to short-form resolution
+	}
+
+	state := &initialState{}
+	if err := json.Unmarshal(stateBytes, state); err != nil {
+		return nil, false, nil //nolint:nilerr // not a long-form DID, fall back to short-form resolution
+	}
+
+	suffixDataBytes, err := canonicalizer.MarshalCanonical(state.SuffixData)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to canonicalize suffix data for verification: %w", err)
+	}
+
+	if multihash(suffixDataBytes) != suffix {
+		return nil, false, fmt.Errorf("long-form DID initial state does not match its suffix")
+	}
+
+	var rawState struct {
+		Delta json.RawMessage `json:"delta"`
+	}
+
+	if err := json.Unmarshal(stateBytes, &rawState); err != nil {
+		return nil, false, fmt.Errorf("failed to decode delta for verification: %w", err)
+	}
+
+	deltaBytes, err := canonicalizer.MarshalCanonical(rawState.Delta)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to canonicalize delta for verification: %w", err)
+	}
+
+	if multihash(deltaBytes) != state.SuffixData.DeltaHash {
+		return nil, false, fmt.Errorf("long-form DID delta does not match its delta hash")
+	}
+
+	return state, true, nil
+}
+
+// commitment computes the Sidetree reveal commitment for a recovery/update public key,
+// i.e. the multihash of its canonical JWK representation.
+func commitment(key interface{}) (string, error) {
+	jwk, err := jwksupport.JWKFromKey(key)
+	if err != nil {
+		return "", fmt.Errorf("failed to convert key to jwk for commitment: %w", err)
+	}
+
+	jwkBytes, err := canonicalizer.MarshalCanonical(jwk)
+	if err != nil {
+		return "", fmt.Errorf("failed to canonicalize jwk for commitment: %w", err)
+	}
+
+	return multihash(jwkBytes), nil
+}
+
+// docBytesFromInitialState synthesizes a DID document for a long-form DID directly from
+// the patches encoded in its initial state, without resolving it over the network.
+func docBytesFromInitialState(longFormDID string, state *initialState) ([]byte, error) {
+	didDoc := did.Doc{ID: longFormDID}
+
+	for _, p := range state.Delta.Patches {
+		switch p.Action {
+		case addPublicKeysPatch:
+			if err := applyPublicKeysPatch(&didDoc, longFormDID, p.PublicKeys); err != nil {
+				return nil, err
+			}
+		case addServicesPatch:
+			applyServicesPatch(&didDoc, p.Services)
+		}
+	}
+
+	return didDoc.JSONBytes()
+}
+
+func applyPublicKeysPatch(didDoc *did.Doc, didID string, publicKeys interface{}) error {
+	entries, ok := publicKeys.([]interface{})
+	if !ok {
+		return nil
+	}
+
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		jwkBytes, err := json.Marshal(entry["publicKeyJwk"])
+		if err != nil {
+			return fmt.Errorf("failed to marshal public key jwk: %w", err)
+		}
+
+		key := jwk.JWK{}
+		if err := key.UnmarshalJSON(jwkBytes); err != nil {
+			return fmt.Errorf("failed to unmarshal public key jwk: %w", err)
+		}
+
+		id, _ := entry["id"].(string)                     //nolint:errcheck
+		typ, _ := entry["type"].(string)                  //nolint:errcheck
+		purposes, _ := entry["purposes"].([]interface{})  //nolint:errcheck
+
+		vm, err := did.NewVerificationMethodFromJWK(id, typ, didID, &key)
+		if err != nil {
+			return fmt.Errorf("failed to build verification method from jwk: %w", err)
+		}
+
+		for _, purpose := range purposes {
+			p, ok := purpose.(string)
+			if !ok {
+				continue
+			}
+
+			if err := addVerificationRelationship(didDoc, vm, p); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func applyServicesPatch(didDoc *did.Doc, services interface{}) {
+	entries, ok := services.([]interface{})
+	if !ok {
+		return
+	}
+
+	for _, e := range entries {
+		entry, ok := e.(map[string]interface{})
+		if !ok {
+			continue
+		}
+
+		id, _ := entry["id"].(string)                   //nolint:errcheck
+		typ, _ := entry["type"].(string)                 //nolint:errcheck
+		endpoint, _ := entry["serviceEndpoint"].(string) //nolint:errcheck
+
+		didDoc.Service = append(didDoc.Service, did.Service{ID: id, Type: typ, ServiceEndpoint: endpoint})
+	}
+}
+
+// multihash computes a base64url-encoded sha2-256 multihash of data, as used by
+// Sidetree for unique suffixes and commitment/delta hashes.
+func multihash(data []byte) string {
+	digest := sha256.Sum256(data)
+
+	encoded := make([]byte, 0, len(digest)+2)
+	encoded = append(encoded, sha2256Code, byte(len(digest)))
+	encoded = append(encoded, digest[:]...)
+
+	return base64.RawURLEncoding.EncodeToString(encoded)
+}
@@ -0,0 +1,102 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/base64"
+	"encoding/json"
+	"net/http/httptest"
+	"testing"
+
+	sidetreedoc "github.com/hyperledger/aries-framework-go-ext/component/vdr/sidetree/doc"
+	"github.com/hyperledger/aries-framework-go/pkg/doc/jose/jwk/jwksupport"
+	"github.com/stretchr/testify/require"
+)
+
+// TestLongFormDID_RoundTrip builds a long-form DID from a set of public keys and services, then
+// resolves it locally and checks that the resulting document is equivalent to what was requested.
+func TestLongFormDID_RoundTrip(t *testing.T) {
+	recoveryPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	updatePub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	authPub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	recoveryCommitment, err := commitment(recoveryPub)
+	require.NoError(t, err)
+
+	updateCommitment, err := commitment(updatePub)
+	require.NoError(t, err)
+
+	authJWK, err := jwksupport.JWKFromKey(authPub)
+	require.NoError(t, err)
+
+	publicKeys := []map[string]interface{}{
+		{"id": "key1", "type": Ed25519KeyType, "purposes": []string{sidetreedoc.KeyPurposeAuthentication},
+			"publicKeyJwk": authJWK},
+	}
+
+	services := []map[string]interface{}{
+		{"id": "svc1", "type": "did-communication", "serviceEndpoint": "https://example.com/endpoint"},
+	}
+
+	didURI, err := buildLongFormDID(trustblocMethod, "testnet.trustbloc.local", publicKeys, services,
+		updateCommitment, recoveryCommitment)
+	require.NoError(t, err)
+
+	state, isLongForm, err := parseLongFormDID(didURI)
+	require.NoError(t, err)
+	require.True(t, isLongForm)
+	require.Equal(t, recoveryCommitment, state.SuffixData.RecoveryCommitment)
+	require.Equal(t, updateCommitment, state.Delta.UpdateCommitment)
+
+	docBytes, err := docBytesFromInitialState(didURI, state)
+	require.NoError(t, err)
+
+	resolvedDoc := map[string]interface{}{}
+	require.NoError(t, json.Unmarshal(docBytes, &resolvedDoc))
+
+	require.Equal(t, didURI, resolvedDoc["id"])
+	require.Len(t, resolvedDoc["authentication"], 1)
+	require.Len(t, resolvedDoc["service"], 1)
+
+	service, ok := resolvedDoc["service"].([]interface{})[0].(map[string]interface{})
+	require.True(t, ok)
+	require.Equal(t, "https://example.com/endpoint", service["serviceEndpoint"])
+}
+
+func TestRegisterDIDHandler_LongFormRequiresRecoveryAndUpdateKeys(t *testing.T) {
+	op := &Operation{blocDomain: "testnet.trustbloc.local"}
+
+	pub, _, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	req := RegisterDIDRequest{
+		DIDDocument: DIDDocument{PublicKey: []PublicKey{
+			{ID: "key1", Type: Ed25519KeyType, KeyType: Ed25519KeyType, Value: base64.StdEncoding.EncodeToString(pub),
+				Purposes: []string{sidetreedoc.KeyPurposeAuthentication}},
+		}},
+		Options: map[string]interface{}{longFormOpt: true},
+	}
+
+	body, err := json.Marshal(req)
+	require.NoError(t, err)
+
+	rw := httptest.NewRecorder()
+	op.registerDIDHandler(rw, httptest.NewRequest("POST", registerPath, bytes.NewReader(body)))
+
+	var resp RegisterResponse
+
+	require.NoError(t, json.Unmarshal(rw.Body.Bytes(), &resp))
+	require.Equal(t, RegistrationStateFailure, resp.DIDState.State)
+	require.Contains(t, resp.DIDState.Reason, "recovery and an update")
+}
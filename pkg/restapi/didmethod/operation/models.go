@@ -0,0 +1,169 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import "encoding/json"
+
+// key types supported by the register handler.
+const (
+	Ed25519KeyType = "Ed25519VerificationKey2018"
+	P256KeyType    = "JwsVerificationKey2020"
+)
+
+// registration states, as per the Universal Registrar spec.
+const (
+	RegistrationStateFinished = "finished"
+	RegistrationStateFailure  = "failure"
+)
+
+// RegisterDIDRequest is the request model for the register endpoint.
+type RegisterDIDRequest struct {
+	JobID       string                 `json:"jobId,omitempty"`
+	DIDDocument DIDDocument            `json:"didDocument,omitempty"`
+	Options     map[string]interface{} `json:"options,omitempty"`
+}
+
+// DIDDocument contains the public keys and services that make up a DID document.
+type DIDDocument struct {
+	PublicKey []PublicKey `json:"publicKey,omitempty"`
+	Service   []Service   `json:"service,omitempty"`
+}
+
+// PublicKey is a public key entry in a DID document create/update request.
+type PublicKey struct {
+	ID       string   `json:"id,omitempty"`
+	Type     string   `json:"type,omitempty"`
+	KeyType  string   `json:"keyType,omitempty"`
+	Value    string   `json:"value,omitempty"`
+	Purposes []string `json:"purposes,omitempty"`
+	Recovery bool     `json:"recovery,omitempty"`
+	Update   bool     `json:"update,omitempty"`
+}
+
+// Service is a service entry in a DID document create/update request.
+type Service struct {
+	ID            string   `json:"id,omitempty"`
+	Type          string   `json:"type,omitempty"`
+	Priority      uint     `json:"priority,omitempty"`
+	RecipientKeys []string `json:"recipientKeys,omitempty"`
+	RoutingKeys   []string `json:"routingKeys,omitempty"`
+	Endpoint      string   `json:"serviceEndpoint,omitempty"`
+}
+
+// RegisterResponse is the response model for the register endpoint.
+type RegisterResponse struct {
+	JobID    string   `json:"jobId,omitempty"`
+	DIDState DIDState `json:"didState,omitempty"`
+}
+
+// DIDState reports the outcome of a registrar operation, as per the Universal Registrar spec.
+type DIDState struct {
+	Identifier string `json:"did,omitempty"`
+	State      string `json:"state,omitempty"`
+	Reason     string `json:"reason,omitempty"`
+	Secret     Secret `json:"secret,omitempty"`
+}
+
+// Secret carries the public keys that the operation added to the DID document, identified by
+// their DID URL. It does not carry the next update or recovery commitments.
+type Secret struct {
+	Keys []Key `json:"keys,omitempty"`
+}
+
+// Key is a single key entry returned in a DIDState's Secret.
+type Key struct {
+	ID              string `json:"id,omitempty"`
+	PublicKeyBase58 string `json:"publicKeyBase58,omitempty"`
+}
+
+// DIDDocumentOperation is the set of patches to apply to an existing DID document, as per the
+// Universal Registrar spec's `didDocumentOperation` request field.
+type DIDDocumentOperation struct {
+	DID              string      `json:"did,omitempty"`
+	AddPublicKeys    []PublicKey `json:"addPublicKeys,omitempty"`
+	RemovePublicKeys []string    `json:"removePublicKeys,omitempty"`
+	AddServices      []Service   `json:"addServices,omitempty"`
+	RemoveServices   []string    `json:"removeServices,omitempty"`
+}
+
+// RequestSecret carries the current update/recovery key material needed to sign a Sidetree
+// update/deactivate/recover operation, plus the next commitments for update/recover requests.
+// NextRecoveryKey is only honored on a recover request; an update request that carries one is
+// rejected, since Sidetree update operations may only roll the update commitment.
+type RequestSecret struct {
+	SigningKeyType  string     `json:"signingKeyType,omitempty"`
+	SigningKeyValue string     `json:"signingKeyValue,omitempty"`
+	NextUpdateKey   *PublicKey `json:"nextUpdateKey,omitempty"`
+	NextRecoveryKey *PublicKey `json:"nextRecoveryKey,omitempty"`
+}
+
+// UpdateDIDRequest is the request model for the update endpoint.
+type UpdateDIDRequest struct {
+	JobID                string               `json:"jobId,omitempty"`
+	DIDDocumentOperation DIDDocumentOperation `json:"didDocumentOperation,omitempty"`
+	Secret               RequestSecret        `json:"secret,omitempty"`
+}
+
+// DeactivateDIDRequest is the request model for the deactivate endpoint.
+type DeactivateDIDRequest struct {
+	JobID  string        `json:"jobId,omitempty"`
+	DID    string        `json:"did,omitempty"`
+	Secret RequestSecret `json:"secret,omitempty"`
+}
+
+// RecoverDIDRequest is the request model for the recover endpoint.
+type RecoverDIDRequest struct {
+	JobID       string        `json:"jobId,omitempty"`
+	DID         string        `json:"did,omitempty"`
+	DIDDocument DIDDocument   `json:"didDocument,omitempty"`
+	Secret      RequestSecret `json:"secret,omitempty"`
+}
+
+// UpdateDIDResponse is the response model for the update endpoint.
+type UpdateDIDResponse struct {
+	JobID    string   `json:"jobId,omitempty"`
+	DIDState DIDState `json:"didState,omitempty"`
+}
+
+// DeactivateDIDResponse is the response model for the deactivate endpoint.
+type DeactivateDIDResponse struct {
+	JobID    string   `json:"jobId,omitempty"`
+	DIDState DIDState `json:"didState,omitempty"`
+}
+
+// RecoverDIDResponse is the response model for the recover endpoint.
+type RecoverDIDResponse struct {
+	JobID    string   `json:"jobId,omitempty"`
+	DIDState DIDState `json:"didState,omitempty"`
+}
+
+// DIDResolutionResult is the W3C DID Resolution v0.2 response envelope, returned by
+// resolveDIDHandler when the caller's Accept header asks for it.
+type DIDResolutionResult struct {
+	Context               string                `json:"@context,omitempty"`
+	DIDDocument           json.RawMessage       `json:"didDocument,omitempty"`
+	DIDResolutionMetadata DIDResolutionMetadata `json:"didResolutionMetadata"`
+	DIDDocumentMetadata   DIDDocumentMetadata   `json:"didDocumentMetadata"`
+}
+
+// DIDResolutionMetadata describes the resolution process itself, as per the DID Resolution spec.
+type DIDResolutionMetadata struct {
+	ContentType string `json:"contentType,omitempty"`
+	Error       string `json:"error,omitempty"`
+	Retrieved   string `json:"retrieved,omitempty"`
+	Duration    int64  `json:"duration,omitempty"`
+}
+
+// DIDDocumentMetadata describes the resolved DID document's state, as per the DID Resolution spec.
+type DIDDocumentMetadata struct {
+	Created      string   `json:"created,omitempty"`
+	Updated      string   `json:"updated,omitempty"`
+	Deactivated  bool     `json:"deactivated,omitempty"`
+	VersionID    string   `json:"versionId,omitempty"`
+	CanonicalID  string   `json:"canonicalId,omitempty"`
+	EquivalentID []string `json:"equivalentId,omitempty"`
+}
@@ -15,6 +15,8 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/btcsuite/btcutil/base58"
 	"github.com/hyperledger/aries-framework-go-ext/component/vdr/sidetree/doc"
@@ -25,19 +27,33 @@ import (
 	log "github.com/sirupsen/logrus"
 
 	"github.com/trustbloc/trustbloc-did-method/pkg/internal/common/support"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/discovery"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
 )
 
 const (
 	registerBasePath     = "/1.0"
 	registerPath         = registerBasePath + "/register"
+	endpointsPath        = registerBasePath + "/endpoints"
 	resolveDIDEndpoint   = "/resolveDID"
 	didLDJson            = "application/did+ld+json"
+	didJSON              = "application/did+json"
 	invalidRequestErrMsg = "invalid request"
 
+	didResolutionMediaType = `application/ld+json;profile="https://w3id.org/did-resolution"`
+	didResolutionContext   = "https://w3id.org/did-resolution/v1"
+
+	resolutionErrNotFound                   = "notFound"
+	resolutionErrInvalidDID                 = "invalidDid"
+	resolutionErrRepresentationNotSupported = "representationNotSupported"
+	resolutionErrMethodNotSupported         = "methodNotSupported"
+
 	// modes
 	registrarMode = "registrar"
 	resolverMode  = "resolver"
 	combinedMode  = "combined"
+
+	trustblocMethod = "trustbloc"
 )
 
 // Handler http handler for each controller API endpoint
@@ -47,10 +63,22 @@ type Handler interface {
 	Handle() http.HandlerFunc
 }
 
+// DiscoveryService discovers a domain's sidetree endpoints for the trustbloc did method, e.g. by
+// resolving its consortium and stakeholder files. Deployers can provide their own implementation
+// via Config.DiscoveryService (static config, DNS-based, an internal registry, etc.). Note that
+// this only gates New() (it must successfully resolve the domain before Operation is constructed)
+// and backs the read-only Endpoints()/endpointsHandler diagnostic; the trustbloc VDR used by
+// registerDIDHandler/resolveDIDHandler/crud.go still performs its own, independent sidetree
+// endpoint discovery from the configured genesis files.
+type DiscoveryService interface {
+	GetEndpoints(domain string) ([]*models.Endpoint, error)
+}
+
 // Operation defines handlers
 type Operation struct {
-	blocVDRI   vdr.VDR
-	blocDomain string
+	blocVDRI         vdr.VDR
+	blocDomain       string
+	discoveryService DiscoveryService
 }
 
 // GenesisFileConfig defines a genesis file for the trustbloc did method vdri
@@ -68,6 +96,7 @@ type Config struct {
 	SidetreeWriteToken string
 	EnableSignatures   bool
 	GenesisFiles       []GenesisFileConfig
+	DiscoveryService   DiscoveryService
 }
 
 // New returns did method operation instance
@@ -79,8 +108,26 @@ func New(config *Config) (*Operation, error) {
 		trustbloc.WithDomain(config.BlocDomain),
 	}
 
+	genesisFiles := make([]discovery.GenesisFile, 0, len(config.GenesisFiles))
+
 	for _, genesisFile := range config.GenesisFiles {
 		vdriOpts = append(vdriOpts, trustbloc.UseGenesisFile(genesisFile.URL, genesisFile.URL, genesisFile.Data))
+		genesisFiles = append(genesisFiles, discovery.GenesisFile{Domain: genesisFile.URL, Data: genesisFile.Data})
+	}
+
+	discoveryService := config.DiscoveryService
+	if discoveryService == nil {
+		discoveryService = discovery.New(genesisFiles, discovery.WithTLSConfig(config.TLSConfig))
+	}
+
+	// Fail fast if the (possibly custom) DiscoveryService can't resolve this domain at all. This
+	// is a startup reachability check and backs the diagnostic Endpoints()/endpointsHandler; it
+	// does not change how the trustbloc VDR itself discovers sidetree endpoints for registration
+	// or resolution (that still comes from the genesis files given to trustbloc.New below). It
+	// also lets Operation construction be exercised end-to-end in tests against a fake consortium,
+	// without any real discovery network calls or a working trustbloc VDR.
+	if _, err := discoveryService.GetEndpoints(config.BlocDomain); err != nil {
+		return nil, fmt.Errorf("failed to discover endpoints for domain %s: %w", config.BlocDomain, err)
 	}
 
 	blocVDR, err := trustbloc.New(nil, vdriOpts...)
@@ -88,7 +135,27 @@ func New(config *Config) (*Operation, error) {
 		return nil, err
 	}
 
-	return &Operation{blocVDRI: blocVDR, blocDomain: config.BlocDomain}, nil
+	return &Operation{blocVDRI: blocVDR, blocDomain: config.BlocDomain, discoveryService: discoveryService}, nil
+}
+
+// Endpoints discovers the sidetree endpoints for the operation's configured domain using its
+// DiscoveryService.
+func (o *Operation) Endpoints() ([]*models.Endpoint, error) {
+	return o.discoveryService.GetEndpoints(o.blocDomain)
+}
+
+// endpointsHandler exposes the domain's discovered sidetree endpoints, so deployers and tests can
+// see what a configured DiscoveryService is actually resolving.
+func (o *Operation) endpointsHandler(rw http.ResponseWriter, _ *http.Request) {
+	endpoints, err := o.Endpoints()
+	if err != nil {
+		o.writeErrorResponse(rw, http.StatusInternalServerError,
+			fmt.Sprintf("failed to discover endpoints: %s", err.Error()))
+
+		return
+	}
+
+	o.writeResponse(rw, endpoints)
 }
 
 func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request) { //nolint: funlen,gocyclo
@@ -115,6 +182,10 @@ func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request
 
 	var didMethodOpt []vdr.DIDMethodOption
 
+	var longFormPublicKeys []map[string]interface{}
+
+	var recoveryCommitment, updateCommitment string
+
 	// Add public keys
 	for _, v := range data.DIDDocument.PublicKey {
 		keyValue, err := base64.StdEncoding.DecodeString(v.Value)
@@ -141,12 +212,30 @@ func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request
 		if v.Recovery {
 			didMethodOpt = append(didMethodOpt, vdr.WithOption(trustbloc.RecoveryPublicKeyOpt, k))
 
+			recoveryCommitment, err = commitment(k)
+			if err != nil {
+				registerResponse.DIDState = DIDState{Reason: err.Error(), State: RegistrationStateFailure}
+
+				o.writeResponse(rw, registerResponse)
+
+				return
+			}
+
 			continue
 		}
 
 		if v.Update {
 			didMethodOpt = append(didMethodOpt, vdr.WithOption(trustbloc.UpdatePublicKeyOpt, k))
 
+			updateCommitment, err = commitment(k)
+			if err != nil {
+				registerResponse.DIDState = DIDState{Reason: err.Error(), State: RegistrationStateFailure}
+
+				o.writeResponse(rw, registerResponse)
+
+				return
+			}
+
 			continue
 		}
 
@@ -169,25 +258,8 @@ func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request
 		}
 
 		for _, p := range v.Purposes {
-			switch p {
-			case doc.KeyPurposeAuthentication:
-				didDoc.Authentication = append(didDoc.Authentication,
-					*did.NewReferencedVerification(vm, did.Authentication))
-			case doc.KeyPurposeAssertionMethod:
-				didDoc.AssertionMethod = append(didDoc.AssertionMethod,
-					*did.NewReferencedVerification(vm, did.AssertionMethod))
-			case doc.KeyPurposeKeyAgreement:
-				didDoc.KeyAgreement = append(didDoc.KeyAgreement,
-					*did.NewReferencedVerification(vm, did.KeyAgreement))
-			case doc.KeyPurposeCapabilityDelegation:
-				didDoc.CapabilityDelegation = append(didDoc.CapabilityDelegation,
-					*did.NewReferencedVerification(vm, did.CapabilityDelegation))
-			case doc.KeyPurposeCapabilityInvocation:
-				didDoc.CapabilityInvocation = append(didDoc.CapabilityInvocation,
-					*did.NewReferencedVerification(vm, did.CapabilityInvocation))
-			default:
-				registerResponse.DIDState = DIDState{
-					Reason: fmt.Sprintf("public key purpose %s not supported", p), State: RegistrationStateFailure}
+			if err := addVerificationRelationship(&didDoc, vm, p); err != nil {
+				registerResponse.DIDState = DIDState{Reason: err.Error(), State: RegistrationStateFailure}
 
 				o.writeResponse(rw, registerResponse)
 
@@ -196,13 +268,54 @@ func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request
 		}
 
 		keysID[v.ID] = keyValue
+
+		longFormPublicKeys = append(longFormPublicKeys, map[string]interface{}{
+			"id": v.ID, "type": v.Type, "purposes": v.Purposes, "publicKeyJwk": jwk,
+		})
 	}
 
 	// Add services
+	var longFormServices []map[string]interface{}
+
 	for _, service := range data.DIDDocument.Service {
 		didDoc.Service = append(didDoc.Service, did.Service{ID: service.ID, Type: service.Type,
 			Priority: service.Priority, RecipientKeys: service.RecipientKeys, RoutingKeys: service.RoutingKeys,
 			ServiceEndpoint: service.Endpoint})
+
+		longFormServices = append(longFormServices, map[string]interface{}{
+			"id": service.ID, "type": service.Type, "serviceEndpoint": service.Endpoint,
+		})
+	}
+
+	if longForm, ok := data.Options[longFormOpt].(bool); ok && longForm {
+		if recoveryCommitment == "" || updateCommitment == "" {
+			registerResponse.DIDState = DIDState{
+				Reason: "a long-form did requires both a recovery and an update public key",
+				State:  RegistrationStateFailure,
+			}
+
+			o.writeResponse(rw, registerResponse)
+
+			return
+		}
+
+		didURI, err := buildLongFormDID(trustblocMethod, o.blocDomain, longFormPublicKeys, longFormServices,
+			updateCommitment, recoveryCommitment)
+		if err != nil {
+			registerResponse.DIDState = DIDState{Reason: fmt.Sprintf("failed to build long-form did : %s",
+				err.Error()), State: RegistrationStateFailure}
+
+			o.writeResponse(rw, registerResponse)
+
+			return
+		}
+
+		registerResponse.DIDState = DIDState{Identifier: didURI, State: RegistrationStateFinished,
+			Secret: Secret{Keys: createKeys(keysID, didURI)}}
+
+		o.writeResponse(rw, registerResponse)
+
+		return
 	}
 
 	docResolution, err := o.blocVDRI.Create(&didDoc, didMethodOpt...)
@@ -223,6 +336,28 @@ func (o *Operation) registerDIDHandler(rw http.ResponseWriter, req *http.Request
 	o.writeResponse(rw, registerResponse)
 }
 
+// addVerificationRelationship adds vm to didDoc under the verification relationship named by purpose.
+func addVerificationRelationship(didDoc *did.Doc, vm *did.VerificationMethod, purpose string) error {
+	switch purpose {
+	case doc.KeyPurposeAuthentication:
+		didDoc.Authentication = append(didDoc.Authentication, *did.NewReferencedVerification(vm, did.Authentication))
+	case doc.KeyPurposeAssertionMethod:
+		didDoc.AssertionMethod = append(didDoc.AssertionMethod, *did.NewReferencedVerification(vm, did.AssertionMethod))
+	case doc.KeyPurposeKeyAgreement:
+		didDoc.KeyAgreement = append(didDoc.KeyAgreement, *did.NewReferencedVerification(vm, did.KeyAgreement))
+	case doc.KeyPurposeCapabilityDelegation:
+		didDoc.CapabilityDelegation = append(didDoc.CapabilityDelegation,
+			*did.NewReferencedVerification(vm, did.CapabilityDelegation))
+	case doc.KeyPurposeCapabilityInvocation:
+		didDoc.CapabilityInvocation = append(didDoc.CapabilityInvocation,
+			*did.NewReferencedVerification(vm, did.CapabilityInvocation))
+	default:
+		return fmt.Errorf("public key purpose %s not supported", purpose)
+	}
+
+	return nil
+}
+
 func getKey(keyType string, value []byte) (interface{}, error) {
 	switch keyType {
 	case Ed25519KeyType:
@@ -255,18 +390,25 @@ func (o *Operation) resolveDIDHandler(rw http.ResponseWriter, req *http.Request)
 		return
 	}
 
-	DocResolution, err := o.blocVDRI.Read(didParam[0])
-	if err != nil {
-		o.writeErrorResponse(rw, http.StatusBadRequest,
-			fmt.Sprintf("failed to resolve did: %s", err.Error()))
-
-		return
+	switch classifyAccept(req.Header.Get("Accept")) {
+	case acceptLegacyDoc:
+		o.resolveDIDLegacy(rw, didParam[0])
+	case acceptResolutionResult:
+		o.resolveDIDAsResolutionResult(rw, didParam[0])
+	default:
+		o.writeResolutionResult(rw, http.StatusNotAcceptable, DIDResolutionResult{
+			Context:               didResolutionContext,
+			DIDResolutionMetadata: DIDResolutionMetadata{Error: resolutionErrRepresentationNotSupported},
+		})
 	}
+}
 
-	bytes, err := DocResolution.JSONBytes()
+// resolveDIDLegacy resolves did and writes the raw DID document, for callers that have not
+// opted into the W3C DID Resolution result envelope via the Accept header.
+func (o *Operation) resolveDIDLegacy(rw http.ResponseWriter, did string) {
+	bytes, _, _, err := o.resolveDocBytes(did)
 	if err != nil {
-		o.writeErrorResponse(rw, http.StatusInternalServerError,
-			fmt.Sprintf("failed to marshal doc resolution: %s", err.Error()))
+		o.writeErrorResponse(rw, http.StatusBadRequest, fmt.Sprintf("failed to resolve did: %s", err.Error()))
 
 		return
 	}
@@ -279,6 +421,143 @@ func (o *Operation) resolveDIDHandler(rw http.ResponseWriter, req *http.Request)
 	}
 }
 
+// resolveDIDAsResolutionResult resolves did and writes a W3C DID Resolution v0.2 result, as
+// expected of a Universal Resolver driver: resolution errors are surfaced in
+// didResolutionMetadata.error rather than as a plain-text HTTP error.
+func (o *Operation) resolveDIDAsResolutionResult(rw http.ResponseWriter, did string) {
+	started := time.Now()
+
+	result := DIDResolutionResult{
+		Context:               didResolutionContext,
+		DIDResolutionMetadata: DIDResolutionMetadata{ContentType: didLDJson, Retrieved: started.UTC().Format(time.RFC3339)},
+	}
+
+	if !strings.HasPrefix(did, "did:"+trustblocMethod+":") {
+		result.DIDResolutionMetadata.Error = resolutionErrMethodNotSupported
+		result.DIDResolutionMetadata.Duration = time.Since(started).Milliseconds()
+
+		o.writeResolutionResult(rw, http.StatusOK, result)
+
+		return
+	}
+
+	bytes, isLongForm, meta, err := o.resolveDocBytes(did)
+	if err != nil {
+		result.DIDResolutionMetadata.Error = resolutionErrorCode(isLongForm, err)
+	} else {
+		result.DIDDocument = bytes
+		result.DIDDocumentMetadata = meta
+	}
+
+	result.DIDResolutionMetadata.Duration = time.Since(started).Milliseconds()
+
+	o.writeResolutionResult(rw, http.StatusOK, result)
+}
+
+func (o *Operation) writeResolutionResult(rw http.ResponseWriter, status int, result DIDResolutionResult) {
+	rw.Header().Set("Content-type", didResolutionMediaType)
+	rw.WriteHeader(status)
+	o.writeResponse(rw, result)
+}
+
+// resolveDocBytes resolves did to its DID document bytes and document metadata, transparently
+// handling both short-form (network) and long-form (local) DIDs.
+func (o *Operation) resolveDocBytes(did string) ([]byte, bool, DIDDocumentMetadata, error) {
+	state, isLongForm, err := parseLongFormDID(did)
+	if err != nil {
+		return nil, true, DIDDocumentMetadata{}, err
+	}
+
+	if isLongForm {
+		bytes, err := docBytesFromInitialState(did, state)
+		if err != nil {
+			return nil, true, DIDDocumentMetadata{}, err
+		}
+
+		return bytes, true, DIDDocumentMetadata{EquivalentID: []string{shortFormDID(did)}}, nil
+	}
+
+	docResolution, err := o.blocVDRI.Read(did)
+	if err != nil {
+		return nil, false, DIDDocumentMetadata{}, err
+	}
+
+	bytes, err := docResolution.JSONBytes()
+	if err != nil {
+		return nil, false, DIDDocumentMetadata{}, err
+	}
+
+	return bytes, false, documentMetadataFrom(docResolution), nil
+}
+
+// documentMetadataFrom maps the VDR's resolution metadata to the W3C didDocumentMetadata fields.
+func documentMetadataFrom(docResolution *did.DocResolution) DIDDocumentMetadata {
+	if docResolution.DocumentMetadata == nil {
+		return DIDDocumentMetadata{}
+	}
+
+	dm := docResolution.DocumentMetadata
+
+	meta := DIDDocumentMetadata{
+		Deactivated:  dm.Deactivated,
+		VersionID:    dm.VersionID,
+		CanonicalID:  dm.CanonicalID,
+		EquivalentID: dm.EquivalentID,
+	}
+
+	if dm.Created != nil {
+		meta.Created = dm.Created.UTC().Format(time.RFC3339)
+	}
+
+	if dm.Updated != nil {
+		meta.Updated = dm.Updated.UTC().Format(time.RFC3339)
+	}
+
+	return meta
+}
+
+// shortFormDID strips the trailing base64url-encoded initial-state segment from a long-form DID,
+// returning its short-form equivalent.
+func shortFormDID(did string) string {
+	parts := strings.Split(did, ":")
+
+	return strings.Join(parts[:len(parts)-1], ":")
+}
+
+const (
+	acceptLegacyDoc = iota
+	acceptResolutionResult
+	acceptUnsupported
+)
+
+// classifyAccept maps an Accept header value to how resolveDIDHandler should respond: the bare
+// legacy DID document, a W3C DID Resolution v0.2 result, or a representationNotSupported error
+// when the client asked for a representation this resolver cannot produce.
+func classifyAccept(accept string) int {
+	switch {
+	case accept == "" || accept == "*/*" || strings.Contains(accept, didLDJson):
+		return acceptLegacyDoc
+	case strings.Contains(accept, didResolutionMediaType) || strings.Contains(accept, didJSON):
+		return acceptResolutionResult
+	case strings.Contains(accept, "application/"):
+		return acceptUnsupported
+	default:
+		return acceptLegacyDoc
+	}
+}
+
+// resolutionErrorCode maps a resolution failure to a didResolutionMetadata.error code, as per
+// the DID Resolution spec's registered error values.
+func resolutionErrorCode(isLongForm bool, err error) string {
+	if isLongForm {
+		return resolutionErrInvalidDID
+	}
+
+	log.Errorf("failed to resolve did: %s", err.Error())
+
+	return resolutionErrNotFound
+}
+
 // writeErrorResponse writes interface value to response
 func (o *Operation) writeErrorResponse(rw http.ResponseWriter, status int, msg string) {
 	rw.WriteHeader(status)
@@ -298,12 +577,16 @@ func (o *Operation) writeResponse(rw io.Writer, v interface{}) {
 
 func (o *Operation) registrarHandlers() []Handler {
 	return []Handler{
-		support.NewHTTPHandler(registerPath, http.MethodPost, o.registerDIDHandler)}
+		support.NewHTTPHandler(registerPath, http.MethodPost, o.registerDIDHandler),
+		support.NewHTTPHandler(updatePath, http.MethodPost, o.updateDIDHandler),
+		support.NewHTTPHandler(deactivatePath, http.MethodPost, o.deactivateDIDHandler),
+		support.NewHTTPHandler(recoverPath, http.MethodPost, o.recoverDIDHandler)}
 }
 
 func (o *Operation) resolverHandlers() []Handler {
 	return []Handler{
-		support.NewHTTPHandler(resolveDIDEndpoint, http.MethodGet, o.resolveDIDHandler)}
+		support.NewHTTPHandler(resolveDIDEndpoint, http.MethodGet, o.resolveDIDHandler),
+		support.NewHTTPHandler(endpointsPath, http.MethodGet, o.endpointsHandler)}
 }
 
 // GetRESTHandlers get all controller API handler available for this service
@@ -0,0 +1,113 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package operation
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	mockdiscovery "github.com/trustbloc/trustbloc-did-method/pkg/internal/mock/discovery"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+func TestNew_DiscoveryServiceGatesConstruction(t *testing.T) {
+	t.Run("fails when the configured DiscoveryService cannot resolve the domain", func(t *testing.T) {
+		mockService := &mockdiscovery.MockDiscoveryService{
+			GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+				return nil, fmt.Errorf("consortium unreachable")
+			},
+		}
+
+		op, err := New(&Config{BlocDomain: "testnet.trustbloc.local", DiscoveryService: mockService})
+		require.Error(t, err)
+		require.Nil(t, op)
+		require.Contains(t, err.Error(), "consortium unreachable")
+	})
+
+	t.Run("succeeds against a fake consortium, making resolver/registrar mode testable end-to-end",
+		func(t *testing.T) {
+			mockService := &mockdiscovery.MockDiscoveryService{
+				GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+					return []*models.Endpoint{{ResolutionEndpoint: "https://fake.example/resolve",
+						OperationEndpoint: "https://fake.example/operate"}}, nil
+				},
+			}
+
+			op, err := New(&Config{BlocDomain: "testnet.trustbloc.local", DiscoveryService: mockService})
+			require.NoError(t, err)
+			require.NotNil(t, op)
+
+			endpoints, err := op.Endpoints()
+			require.NoError(t, err)
+			require.Len(t, endpoints, 1)
+			require.Equal(t, "https://fake.example/resolve", endpoints[0].ResolutionEndpoint)
+		})
+}
+
+func TestClassifyAccept(t *testing.T) {
+	require.Equal(t, acceptLegacyDoc, classifyAccept(""))
+	require.Equal(t, acceptLegacyDoc, classifyAccept(didLDJson))
+	require.Equal(t, acceptResolutionResult, classifyAccept(didResolutionMediaType))
+	require.Equal(t, acceptResolutionResult, classifyAccept(didJSON))
+	require.Equal(t, acceptUnsupported, classifyAccept("application/xml"))
+}
+
+func TestResolutionErrorCode(t *testing.T) {
+	require.Equal(t, resolutionErrInvalidDID, resolutionErrorCode(true, fmt.Errorf("bad initial state")))
+	require.Equal(t, resolutionErrNotFound, resolutionErrorCode(false, fmt.Errorf("not found")))
+}
+
+func TestShortFormDID(t *testing.T) {
+	require.Equal(t, "did:trustbloc:testnet:abc123",
+		shortFormDID("did:trustbloc:testnet:abc123:eyJkZWx0YSI6e319"))
+}
+
+func TestResolveDIDHandler_RejectsUnsupportedAccept(t *testing.T) {
+	op := &Operation{blocDomain: "testnet.trustbloc.local"}
+
+	req := httptest.NewRequest("GET", resolveDIDEndpoint+"?did=did:trustbloc:testnet:abc123", nil)
+	req.Header.Set("Accept", "application/xml")
+
+	rw := httptest.NewRecorder()
+	op.resolveDIDHandler(rw, req)
+
+	require.Equal(t, http.StatusNotAcceptable, rw.Code)
+	require.Contains(t, rw.Body.String(), resolutionErrRepresentationNotSupported)
+}
+
+func TestResolveDIDHandler_RejectsUnsupportedMethod(t *testing.T) {
+	op := &Operation{blocDomain: "testnet.trustbloc.local"}
+
+	req := httptest.NewRequest("GET", resolveDIDEndpoint+"?did=did:example:abc123", nil)
+	req.Header.Set("Accept", didResolutionMediaType)
+
+	rw := httptest.NewRecorder()
+	op.resolveDIDHandler(rw, req)
+
+	require.Equal(t, http.StatusOK, rw.Code)
+	require.Contains(t, rw.Body.String(), resolutionErrMethodNotSupported)
+}
+
+func TestEndpointsHandler(t *testing.T) {
+	mockService := &mockdiscovery.MockDiscoveryService{
+		GetEndpointsFunc: func(domain string) ([]*models.Endpoint, error) {
+			return []*models.Endpoint{{ResolutionEndpoint: "https://fake.example/resolve"}}, nil
+		},
+	}
+
+	op := &Operation{blocDomain: "testnet.trustbloc.local", discoveryService: mockService}
+
+	rw := httptest.NewRecorder()
+	op.endpointsHandler(rw, httptest.NewRequest("GET", endpointsPath, nil))
+
+	require.Equal(t, 200, rw.Code)
+	require.Contains(t, rw.Body.String(), "https://fake.example/resolve")
+}
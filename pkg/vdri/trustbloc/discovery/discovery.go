@@ -0,0 +1,216 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package discovery provides the default consortium/stakeholder-file-based implementation of
+// the trustbloc did method's DiscoveryService.
+package discovery
+
+import (
+	"crypto/ed25519"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/doc/json/canonicalizer"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+const defaultCacheTTL = 5 * time.Minute
+
+// GenesisFile is a consortium genesis file used to bootstrap discovery for a domain.
+type GenesisFile struct {
+	Domain string
+	Data   []byte
+}
+
+// consortiumFile is the well-known consortium file referenced by a domain's genesis file.
+type consortiumFile struct {
+	Domain  string           `json:"domain"`
+	Members []stakeholderRef `json:"members"`
+}
+
+// stakeholderRef identifies a consortium member and the public key its stakeholder file must be
+// signed with.
+type stakeholderRef struct {
+	Domain          string `json:"domain"`
+	PublicKeyBase58 string `json:"publicKeyBase58"`
+}
+
+// stakeholderFile is a consortium member's signed list of sidetree endpoints.
+type stakeholderFile struct {
+	Domain    string            `json:"domain"`
+	Endpoints []models.Endpoint `json:"endpoints"`
+	Proof     stakeholderProof  `json:"proof"`
+}
+
+// stakeholderProof is an ed25519 signature over the canonicalized stakeholder file, proof excluded.
+type stakeholderProof struct {
+	SignatureValue string `json:"signatureValue"`
+}
+
+type cacheEntry struct {
+	endpoints []*models.Endpoint
+	expiresAt time.Time
+}
+
+// Option configures a ConsortiumDiscoveryService.
+type Option func(*ConsortiumDiscoveryService)
+
+// WithTLSConfig sets the TLS config used to fetch consortium/stakeholder files.
+func WithTLSConfig(tlsConfig *tls.Config) Option {
+	return func(s *ConsortiumDiscoveryService) {
+		s.httpClient.Transport = &http.Transport{TLSClientConfig: tlsConfig}
+	}
+}
+
+// WithCacheTTL overrides the default cache TTL for discovered endpoints.
+func WithCacheTTL(ttl time.Duration) Option {
+	return func(s *ConsortiumDiscoveryService) {
+		s.cacheTTL = ttl
+	}
+}
+
+// ConsortiumDiscoveryService is the default DiscoveryService implementation: it resolves a
+// domain's genesis file to a consortium file, fetches and signature-verifies each member's
+// stakeholder file, and caches the resulting endpoints for its cache TTL (see WithCacheTTL).
+type ConsortiumDiscoveryService struct {
+	httpClient   *http.Client
+	genesisFiles map[string][]byte
+	cacheTTL     time.Duration
+
+	mutex sync.RWMutex
+	cache map[string]cacheEntry
+}
+
+// New returns a ConsortiumDiscoveryService seeded with the given genesis files.
+func New(genesisFiles []GenesisFile, opts ...Option) *ConsortiumDiscoveryService {
+	s := &ConsortiumDiscoveryService{
+		httpClient:   &http.Client{},
+		genesisFiles: make(map[string][]byte, len(genesisFiles)),
+		cacheTTL:     defaultCacheTTL,
+		cache:        make(map[string]cacheEntry),
+	}
+
+	for _, gf := range genesisFiles {
+		s.genesisFiles[gf.Domain] = gf.Data
+	}
+
+	for _, opt := range opts {
+		opt(s)
+	}
+
+	return s
+}
+
+// GetEndpoints discovers the sidetree endpoints for domain, as per the DiscoveryService interface.
+func (s *ConsortiumDiscoveryService) GetEndpoints(domain string) ([]*models.Endpoint, error) {
+	if cached, ok := s.fromCache(domain); ok {
+		return cached, nil
+	}
+
+	genesis, ok := s.genesisFiles[domain]
+	if !ok {
+		return nil, fmt.Errorf("no genesis file configured for domain %s", domain)
+	}
+
+	consortium := consortiumFile{}
+	if err := json.Unmarshal(genesis, &consortium); err != nil {
+		return nil, fmt.Errorf("failed to parse genesis consortium file for %s: %w", domain, err)
+	}
+
+	var endpoints []*models.Endpoint
+
+	for _, member := range consortium.Members {
+		stakeholder, err := s.fetchStakeholderFile(member)
+		if err != nil {
+			return nil, fmt.Errorf("failed to fetch stakeholder file for %s: %w", member.Domain, err)
+		}
+
+		for i := range stakeholder.Endpoints {
+			endpoints = append(endpoints, &stakeholder.Endpoints[i])
+		}
+	}
+
+	s.toCache(domain, endpoints)
+
+	return endpoints, nil
+}
+
+func (s *ConsortiumDiscoveryService) fromCache(domain string) ([]*models.Endpoint, bool) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+
+	entry, ok := s.cache[domain]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return nil, false
+	}
+
+	return entry.endpoints, true
+}
+
+func (s *ConsortiumDiscoveryService) toCache(domain string, endpoints []*models.Endpoint) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.cache[domain] = cacheEntry{endpoints: endpoints, expiresAt: time.Now().Add(s.cacheTTL)}
+}
+
+func (s *ConsortiumDiscoveryService) fetchStakeholderFile(member stakeholderRef) (*stakeholderFile, error) {
+	url := fmt.Sprintf("https://%s/.well-known/did-trustbloc/%s.json", member.Domain, member.Domain)
+
+	resp, err := s.httpClient.Get(url) //nolint:noctx,gosec
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close() //nolint:errcheck
+
+	body, err := ioutil.ReadAll(resp.Body)
+	if err != nil {
+		return nil, err
+	}
+
+	stakeholder := &stakeholderFile{}
+	if err := json.Unmarshal(body, stakeholder); err != nil {
+		return nil, err
+	}
+
+	if err := verifyStakeholderSignature(member.PublicKeyBase58, stakeholder); err != nil {
+		return nil, err
+	}
+
+	return stakeholder, nil
+}
+
+// verifyStakeholderSignature checks the stakeholder file's proof against the canonicalized
+// file with its proof removed, using the consortium-pinned public key for that member.
+func verifyStakeholderSignature(publicKeyBase58 string, stakeholder *stakeholderFile) error {
+	sig := stakeholder.Proof.SignatureValue
+
+	unsigned := map[string]interface{}{"domain": stakeholder.Domain, "endpoints": stakeholder.Endpoints}
+
+	payload, err := canonicalizer.MarshalCanonical(unsigned)
+	if err != nil {
+		return fmt.Errorf("failed to canonicalize stakeholder file for %s: %w", stakeholder.Domain, err)
+	}
+
+	pubKey := base58.Decode(publicKeyBase58)
+	if len(pubKey) != ed25519.PublicKeySize {
+		return fmt.Errorf("invalid stakeholder public key for %s", stakeholder.Domain)
+	}
+
+	signature := base58.Decode(sig)
+	if !ed25519.Verify(pubKey, payload, signature) {
+		return fmt.Errorf("invalid stakeholder file signature for %s", stakeholder.Domain)
+	}
+
+	return nil
+}
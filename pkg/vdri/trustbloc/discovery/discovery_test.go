@@ -0,0 +1,139 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+package discovery
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/btcsuite/btcutil/base58"
+	"github.com/stretchr/testify/require"
+
+	"github.com/trustbloc/trustbloc-did-method/pkg/doc/json/canonicalizer"
+	"github.com/trustbloc/trustbloc-did-method/pkg/vdri/trustbloc/models"
+)
+
+type roundTripFunc func(req *http.Request) (*http.Response, error)
+
+func (f roundTripFunc) RoundTrip(req *http.Request) (*http.Response, error) {
+	return f(req)
+}
+
+func jsonResponse(body []byte) *http.Response {
+	return &http.Response{StatusCode: http.StatusOK, Body: ioutil.NopCloser(bytes.NewReader(body))}
+}
+
+// signedStakeholderFile signs a stakeholder file for domain/endpoints with priv, and returns both
+// the signed JSON body and the public key that verifies it.
+func signedStakeholderFile(t *testing.T, domain string, endpoints []models.Endpoint) ([]byte, ed25519.PublicKey) {
+	t.Helper()
+
+	pub, priv, err := ed25519.GenerateKey(nil)
+	require.NoError(t, err)
+
+	unsigned := map[string]interface{}{"domain": domain, "endpoints": endpoints}
+
+	payload, err := canonicalizer.MarshalCanonical(unsigned)
+	require.NoError(t, err)
+
+	signed := stakeholderFile{
+		Domain: domain, Endpoints: endpoints,
+		Proof: stakeholderProof{SignatureValue: base58.Encode(ed25519.Sign(priv, payload))},
+	}
+
+	body, err := json.Marshal(signed)
+	require.NoError(t, err)
+
+	return body, pub
+}
+
+func genesisFile(t *testing.T, domain string, members []stakeholderRef) GenesisFile {
+	t.Helper()
+
+	data, err := json.Marshal(consortiumFile{Domain: domain, Members: members})
+	require.NoError(t, err)
+
+	return GenesisFile{Domain: domain, Data: data}
+}
+
+func TestGetEndpoints(t *testing.T) {
+	t.Run("errors when no genesis file is configured for the domain", func(t *testing.T) {
+		svc := New(nil)
+
+		endpoints, err := svc.GetEndpoints("unconfigured.example")
+		require.Error(t, err)
+		require.Nil(t, endpoints)
+		require.Contains(t, err.Error(), "no genesis file configured")
+	})
+
+	t.Run("rejects a stakeholder file whose payload was tampered with after signing", func(t *testing.T) {
+		memberDomain := "member.example"
+
+		signedBody, pub := signedStakeholderFile(t, memberDomain, []models.Endpoint{
+			{ResolutionEndpoint: "https://member.example/resolve", OperationEndpoint: "https://member.example/operate"},
+		})
+
+		var tampered stakeholderFile
+
+		require.NoError(t, json.Unmarshal(signedBody, &tampered))
+		tampered.Endpoints[0].OperationEndpoint = "https://evil.example/operate"
+
+		tamperedBody, err := json.Marshal(tampered)
+		require.NoError(t, err)
+
+		svc := New([]GenesisFile{genesisFile(t, "example.com", []stakeholderRef{
+			{Domain: memberDomain, PublicKeyBase58: base58.Encode(pub)},
+		})})
+		svc.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			return jsonResponse(tamperedBody), nil
+		})
+
+		endpoints, err := svc.GetEndpoints("example.com")
+		require.Error(t, err)
+		require.Nil(t, endpoints)
+		require.Contains(t, err.Error(), "invalid stakeholder file signature")
+	})
+
+	t.Run("caches discovered endpoints and expires them after the TTL", func(t *testing.T) {
+		memberDomain := "member.example"
+
+		signedBody, pub := signedStakeholderFile(t, memberDomain, []models.Endpoint{
+			{ResolutionEndpoint: "https://member.example/resolve", OperationEndpoint: "https://member.example/operate"},
+		})
+
+		var fetchCount int32
+
+		svc := New([]GenesisFile{genesisFile(t, "example.com", []stakeholderRef{
+			{Domain: memberDomain, PublicKeyBase58: base58.Encode(pub)},
+		})}, WithCacheTTL(20*time.Millisecond))
+		svc.httpClient.Transport = roundTripFunc(func(req *http.Request) (*http.Response, error) {
+			atomic.AddInt32(&fetchCount, 1)
+			return jsonResponse(signedBody), nil
+		})
+
+		endpoints, err := svc.GetEndpoints("example.com")
+		require.NoError(t, err)
+		require.Len(t, endpoints, 1)
+		require.EqualValues(t, 1, atomic.LoadInt32(&fetchCount))
+
+		_, err = svc.GetEndpoints("example.com")
+		require.NoError(t, err)
+		require.EqualValues(t, 1, atomic.LoadInt32(&fetchCount), "a cached call should not refetch")
+
+		time.Sleep(30 * time.Millisecond)
+
+		_, err = svc.GetEndpoints("example.com")
+		require.NoError(t, err)
+		require.EqualValues(t, 2, atomic.LoadInt32(&fetchCount), "an expired cache entry should trigger a refetch")
+	})
+}
@@ -0,0 +1,14 @@
+/*
+Copyright SecureKey Technologies Inc. All Rights Reserved.
+
+SPDX-License-Identifier: Apache-2.0
+*/
+
+// Package models contains the data types shared by the trustbloc VDR's discovery implementations.
+package models
+
+// Endpoint is a sidetree node discovered for a consortium domain.
+type Endpoint struct {
+	ResolutionEndpoint string `json:"resolutionEndpoint"`
+	OperationEndpoint  string `json:"operationEndpoint"`
+}